@@ -0,0 +1,386 @@
+// Package clist provides a goroutine-safe doubly-linked list.
+//
+// The main purpose of this data structure is to allow for concurrent
+// iteration that tolerates concurrent modification: a reader that is
+// blocked waiting for the next element sees new elements pushed to the
+// back of the list instead of having to restart, and a removed element
+// is still usable by readers that already hold a reference to it.
+package clist
+
+import (
+	"fmt"
+	"sync"
+)
+
+/*
+
+CList is a goroutine-safe doubly-linked list. Remove sets CElement.removed
+to true but leaves the element's prev/next pointers in place (only
+DetachPrev/DetachNext clear them), so iterators that are already
+positioned on a removed element are not invalidated: Next()/Prev() walk on
+through its stale pointers, and NextWait()/PrevWait() block at the
+tail/head instead of returning nil, so callers can treat the list as an
+infinite stream.
+
+*/
+
+// CElement is an element of a CList.
+type CElement struct {
+	mtx        sync.RWMutex
+	prev       *CElement
+	prevWg     *sync.WaitGroup
+	prevWaitCh chan struct{}
+	next       *CElement
+	nextWg     *sync.WaitGroup
+	nextWaitCh chan struct{}
+	removed    bool
+
+	Value interface{} // immutable
+}
+
+// Blocking implementation of Next().
+// Blocks while e is the tail; returns nil only if e was removed while it
+// was the tail.
+func (e *CElement) NextWait() *CElement {
+	for {
+		e.mtx.RLock()
+		next := e.next
+		nextWg := e.nextWg
+		removed := e.removed
+		e.mtx.RUnlock()
+
+		if next != nil || removed {
+			return next
+		}
+
+		nextWg.Wait()
+		// e.next doesn't necessarily exist here.
+		// That's why we need to continue a for-loop.
+	}
+}
+
+// Blocking implementation of Prev().
+// Blocks while e is the head; returns nil only if e was removed while it
+// was the head.
+func (e *CElement) PrevWait() *CElement {
+	for {
+		e.mtx.RLock()
+		prev := e.prev
+		prevWg := e.prevWg
+		removed := e.removed
+		e.mtx.RUnlock()
+
+		if prev != nil || removed {
+			return prev
+		}
+
+		prevWg.Wait()
+	}
+}
+
+// PrevWaitChan can be used to wait until Prev becomes not nil. Once it does,
+// channel will be closed.
+func (e *CElement) PrevWaitChan() <-chan struct{} {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+	return e.prevWaitCh
+}
+
+// NextWaitChan can be used to wait until Next becomes not nil. Once it does,
+// channel will be closed.
+func (e *CElement) NextWaitChan() <-chan struct{} {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+	return e.nextWaitCh
+}
+
+// Nonblocking, may return nil if at the end.
+func (e *CElement) Next() *CElement {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+	return e.next
+}
+
+// Nonblocking, may return nil if at the start.
+func (e *CElement) Prev() *CElement {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+	return e.prev
+}
+
+func (e *CElement) Removed() bool {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+	return e.removed
+}
+
+func (e *CElement) DetachNext() {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	if !e.removed {
+		panic("DetachNext() must be called after Remove(e)")
+	}
+	e.next = nil
+}
+
+func (e *CElement) DetachPrev() {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	if !e.removed {
+		panic("DetachPrev() must be called after Remove(e)")
+	}
+	e.prev = nil
+}
+
+// NOTE: This function needs to be safe for concurrent calls with Next() and
+// Prev().
+func (e *CElement) SetNext(newNext *CElement) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	oldNext := e.next
+	e.next = newNext
+	if oldNext != nil && newNext == nil {
+		// A WaitGroup that was released and a closed channel cannot be
+		// re-armed, so waiters on the new tail need a fresh pair.
+		e.nextWg = waitGroup1()
+		e.nextWaitCh = make(chan struct{})
+	}
+	if oldNext == nil && newNext != nil {
+		e.nextWg.Done()
+		close(e.nextWaitCh)
+	}
+}
+
+// NOTE: This function needs to be safe for concurrent calls with Next() and
+// Prev().
+func (e *CElement) SetPrev(newPrev *CElement) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	oldPrev := e.prev
+	e.prev = newPrev
+	if oldPrev != nil && newPrev == nil {
+		e.prevWg = waitGroup1()
+		e.prevWaitCh = make(chan struct{})
+	}
+	if oldPrev == nil && newPrev != nil {
+		e.prevWg.Done()
+		close(e.prevWaitCh)
+	}
+}
+
+func (e *CElement) SetRemoved() {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.removed = true
+
+	// This wakes up anyone waiting in NextWait/PrevWait.
+	if e.next == nil {
+		e.nextWg.Done()
+		close(e.nextWaitCh)
+	}
+	if e.prev == nil {
+		e.prevWg.Done()
+		close(e.prevWaitCh)
+	}
+}
+
+//--------------------------------------------------------------------------------
+
+// CList represents a linked list of CElements.
+//
+// A CList must be created with New or NewWithCap (or have Init called on
+// it) before use; the zero value's wait group is nil.
+//
+// Having head/tail be nil implies the list is empty.
+type CList struct {
+	mtx      sync.RWMutex
+	wg       *sync.WaitGroup
+	waitCh   chan struct{}
+	head     *CElement // first element
+	tail     *CElement // last element
+	len      int       // list length
+	maxLen   int       // max list length (0 if no limit)
+}
+
+func (l *CList) Init() *CList {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.wg = waitGroup1()
+	l.waitCh = make(chan struct{})
+	l.head = nil
+	l.tail = nil
+	l.len = 0
+	return l
+}
+
+func New() *CList {
+	return new(CList).Init()
+}
+
+// NewWithCap creates a new, empty CList whose length is capped at maxLen:
+// PushBack panics once the list already holds maxLen elements. Nothing is
+// pre-allocated.
+func NewWithCap(maxLen int) *CList {
+	l := New()
+	l.maxLen = maxLen
+	return l
+}
+
+func (l *CList) Len() int {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return l.len
+}
+
+func (l *CList) Front() *CElement {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return l.head
+}
+
+func (l *CList) FrontWait() *CElement {
+	// Loop until the head is non-nil else wait and try again
+	for {
+		l.mtx.RLock()
+		head := l.head
+		wg := l.wg
+		l.mtx.RUnlock()
+
+		if head != nil {
+			return head
+		}
+		wg.Wait()
+	}
+}
+
+func (l *CList) Back() *CElement {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return l.tail
+}
+
+func (l *CList) BackWait() *CElement {
+	for {
+		l.mtx.RLock()
+		tail := l.tail
+		wg := l.wg
+		l.mtx.RUnlock()
+
+		if tail != nil {
+			return tail
+		}
+		wg.Wait()
+	}
+}
+
+// WaitChan returns a channel that is closed once entry is made available.
+func (l *CList) WaitChan() <-chan struct{} {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return l.waitCh
+}
+
+// PushBack appends the given value v to the back of the list and returns the
+// corresponding CElement.
+func (l *CList) PushBack(v interface{}) *CElement {
+	l.mtx.Lock()
+
+	if l.maxLen > 0 && l.len >= l.maxLen {
+		l.mtx.Unlock()
+		panic(fmt.Sprintf("clist: PushBack would exceed max length %d", l.maxLen))
+	}
+
+	e := &CElement{
+		prev:       nil,
+		prevWg:     waitGroup1(),
+		prevWaitCh: make(chan struct{}),
+		next:       nil,
+		nextWg:     waitGroup1(),
+		nextWaitCh: make(chan struct{}),
+		removed:    false,
+		Value:      v,
+	}
+
+	// Release waiters on FrontWait/BackWait maybe
+	if l.len == 0 {
+		l.wg.Done()
+		close(l.waitCh)
+	}
+
+	// Modify the tail
+	if l.tail == nil {
+		l.head = e
+		l.tail = e
+	} else {
+		e.SetPrev(l.tail)
+		l.tail.SetNext(e)
+		l.tail = e
+	}
+
+	l.len++
+
+	l.mtx.Unlock()
+	return e
+}
+
+// Remove removes the given element from the list, unblocking any NextWait()
+// or PrevWait() callers positioned on it: they will see the list as it was
+// after the removal instead of panicking or spinning forever.
+func (l *CList) Remove(e *CElement) interface{} {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	prev := e.Prev()
+	next := e.Next()
+
+	if l.head == nil || l.tail == nil {
+		panic("Remove(e) on empty CList")
+	}
+	if prev == nil && l.head != e {
+		panic("Remove(e) with false head")
+	}
+	if next == nil && l.tail != e {
+		panic("Remove(e) with false tail")
+	}
+
+	// If we're removing the only item, make CList FrontWait/BackWait wait.
+	if l.head == e && l.tail == e {
+		l.wg = waitGroup1()
+		l.waitCh = make(chan struct{})
+	}
+
+	// Update l.head
+	if l.head == e {
+		l.head = next
+	}
+	// Update l.tail
+	if l.tail == e {
+		l.tail = prev
+	}
+
+	// Update e.prev and e.next's neighbors
+	if prev != nil {
+		prev.SetNext(next)
+	}
+	if next != nil {
+		next.SetPrev(prev)
+	}
+
+	// Set e.removed=true and emit e.nextWaitCh/e.prevWaitCh if necessary,
+	// so that waiters positioned on this element are woken up.
+	e.SetRemoved()
+
+	l.len--
+
+	return e.Value
+}
+
+func waitGroup1() (wg *sync.WaitGroup) {
+	wg = &sync.WaitGroup{}
+	wg.Add(1)
+	return
+}
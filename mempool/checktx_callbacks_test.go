@@ -0,0 +1,114 @@
+package mempool
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	abciclient "github.com/cometbft/cometbft/abci/client"
+	abciclimocks "github.com/cometbft/cometbft/abci/client/mocks"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	abci "github.com/cometbft/cometbft/api/cometbft/abci/v1"
+	"github.com/cometbft/cometbft/config"
+	"github.com/cometbft/cometbft/internal/test"
+	"github.com/cometbft/cometbft/types"
+)
+
+var errEnqueueFailed = errors.New("proxy app connection is down")
+
+// mockClientWithFailingEnqueue behaves like mockClientWithPerTxGas, except
+// that CheckTxAsync fails synchronously (without ever invoking the response
+// callback) for any tx whose name is in failOn for the given request type.
+func mockClientWithFailingEnqueue(failOn map[string]abci.CheckTxType) *abciclimocks.Client {
+	mockClient, callback := newMockABCIClient()
+
+	mockClient.On("CheckTxAsync", mock.Anything, mock.Anything).Run(
+		func(args mock.Arguments) {
+			req := args.Get(1).(*abci.CheckTxRequest)
+			if failOn[string(req.Tx)] == req.Type {
+				return
+			}
+			abciReq := abcitypes.ToCheckTxRequest(req)
+			resp := &abci.CheckTxResponse{Code: abci.CodeTypeOK, GasWanted: 1}
+			ret := abciclient.NewReqRes(abciReq)
+			ret.Response = abcitypes.ToCheckTxResponse(resp)
+			(*callback)(abciReq, ret.Response)
+		},
+	).Return(func(_ context.Context, req *abci.CheckTxRequest) (*abciclient.ReqRes, error) {
+		if failOn[string(req.Tx)] == req.Type {
+			return nil, errEnqueueFailed
+		}
+		abciReq := abcitypes.ToCheckTxRequest(req)
+		resp := &abci.CheckTxResponse{Code: abci.CodeTypeOK, GasWanted: 1}
+		ret := abciclient.NewReqRes(abciReq)
+		ret.Response = abcitypes.ToCheckTxResponse(resp)
+		return ret, nil
+	})
+
+	return mockClient
+}
+
+func newFailingEnqueueTestMempool(t *testing.T, failOn map[string]abci.CheckTxType, configUpdates func(*config.Config)) *CListMempool {
+	t.Helper()
+	mockClient := mockClientWithFailingEnqueue(failOn)
+	conf := test.ResetTestRoot("mempool_checktx_callbacks_test")
+	conf.Mempool.Recheck = true
+	configUpdates(conf)
+	mp, cleanup := newMempoolWithAppAndConfigMock(conf, mockClient)
+	t.Cleanup(cleanup)
+	return mp
+}
+
+func TestCheckTxErrCbFiresOnEnqueueFailure(t *testing.T) {
+	mp := newFailingEnqueueTestMempool(t, map[string]abci.CheckTxType{"bad": abci.CHECK_TX_TYPE_CHECK}, func(*config.Config) {})
+
+	var gotErr error
+	err := mp.CheckTx(types.Tx("bad"), TxInfo{}, func(e error) { gotErr = e }, func(*abci.CheckTxResponse) {
+		t.Fatal("respCb must not fire when CheckTxAsync fails to enqueue")
+	})
+	require.ErrorIs(t, err, errEnqueueFailed)
+	require.ErrorIs(t, gotErr, errEnqueueFailed)
+	require.Equal(t, 0, mp.Size())
+}
+
+func TestCheckTxRespCbFiresWithResponse(t *testing.T) {
+	mp := newFailingEnqueueTestMempool(t, map[string]abci.CheckTxType{}, func(*config.Config) {})
+
+	var got *abci.CheckTxResponse
+	err := mp.CheckTx(types.Tx("good"), TxInfo{}, func(error) {
+		t.Fatal("errCb must not fire on a successful enqueue")
+	}, func(res *abci.CheckTxResponse) { got = res })
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	require.Equal(t, abci.CodeTypeOK, got.Code)
+	require.Equal(t, 1, mp.Size())
+}
+
+// TestRecheckEnqueueFailureRemovesTxOnNextUpdate verifies that a tx whose
+// recheck submission fails to enqueue is dropped on the next Update rather
+// than leaving the recheck cursor wedged on it forever.
+func TestRecheckEnqueueFailureRemovesTxOnNextUpdate(t *testing.T) {
+	mp := newFailingEnqueueTestMempool(t, map[string]abci.CheckTxType{"flaky": abci.CHECK_TX_TYPE_RECHECK}, func(*config.Config) {})
+
+	err := mp.CheckTx(types.Tx("flaky"), TxInfo{}, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, mp.Size())
+
+	// The first Update triggers the recheck pass whose enqueue fails and
+	// flags the entry; the flag is only acted on starting with the next
+	// Update, so the tx is still present right after this call.
+	mp.Lock()
+	err = mp.Update(1, nil, nil, nil, nil)
+	mp.Unlock()
+	require.NoError(t, err)
+	require.Equal(t, 1, mp.Size())
+
+	mp.Lock()
+	err = mp.Update(2, nil, nil, nil, nil)
+	mp.Unlock()
+	require.NoError(t, err)
+	require.Equal(t, 0, mp.Size(), "tx whose recheck enqueue failed should be dropped")
+}
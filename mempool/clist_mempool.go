@@ -0,0 +1,959 @@
+package mempool
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	abci "github.com/cometbft/cometbft/api/cometbft/abci/v1"
+	"github.com/cometbft/cometbft/config"
+	"github.com/cometbft/cometbft/internal/clist"
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cometbft/cometbft/proxy"
+	"github.com/cometbft/cometbft/types"
+)
+
+// mempoolTx is a transaction that successfully ran CheckTx and is being
+// kept in the mempool, wrapped around the CList entry that stores it.
+//
+// refCount is bumped by SnapshotReap for every ReapSnapshot that pins this
+// entry and dropped by ReapSnapshot.Close. removeTx unlinks the entry from
+// the live CList right away, but its txsMap slot and byte accounting are
+// only released (by maybeFinalizeRemoval) once refCount is back to zero,
+// so concurrent readers never see a transaction disappear under them.
+type mempoolTx struct {
+	height    int64
+	gasWanted int64
+	tx        types.Tx
+
+	refCount       int32 // number of open ReapSnapshots pinning this entry
+	pendingRemoval int32 // 1 once Update has unlinked this entry from the live CList
+	finalized      int32 // 1 once finalizeRemoval has run, guards against running it twice
+	recheckFailed  int32 // 1 if submitRecheck's errCb fired for this entry; removed on the next Update
+
+	priority  int64 // set from PriorityFunc; guarded by CListMempool.priorityMtx
+	heapIndex int   // index into priorityQueue.items; guarded by CListMempool.priorityMtx
+
+	sendersMtx sync.Mutex
+	senders    []uint16 // peer IDs (see mempoolIDs) that have sent this tx
+}
+
+func (memTx *mempoolTx) ref() {
+	atomic.AddInt32(&memTx.refCount, 1)
+}
+
+func (memTx *mempoolTx) unref() {
+	atomic.AddInt32(&memTx.refCount, -1)
+}
+
+// addSender records senderID as having sent this tx, so the reactor's
+// broadcast loop won't gossip it back. It is a no-op if senderID is
+// already recorded.
+func (memTx *mempoolTx) addSender(senderID uint16) {
+	memTx.sendersMtx.Lock()
+	defer memTx.sendersMtx.Unlock()
+	for _, id := range memTx.senders {
+		if id == senderID {
+			return
+		}
+	}
+	memTx.senders = append(memTx.senders, senderID)
+}
+
+// HasSender reports whether senderID has already sent this tx.
+func (memTx *mempoolTx) HasSender(senderID uint16) bool {
+	memTx.sendersMtx.Lock()
+	defer memTx.sendersMtx.Unlock()
+	for _, id := range memTx.senders {
+		if id == senderID {
+			return true
+		}
+	}
+	return false
+}
+
+// CListMempool is an ordered in-memory pool for transactions before they
+// are proposed in a consensus round. Transaction validity is checked using
+// the CheckTx abci message before the transaction is added to the pool.
+// The mempool uses a concurrent list structure for storing transactions
+// that can be efficiently accessed by multiple concurrent readers.
+type CListMempool struct {
+	height   atomic.Int64 // the last block Update()'d to
+	txsBytes atomic.Int64 // total size of mempool, in bytes
+
+	// notify listeners (ie. consensus) when txs are available
+	notifiedTxsAvailable atomic.Bool
+	txsAvailable         chan struct{} // fires once for each height, when the mempool is not empty
+
+	config *config.MempoolConfig
+
+	// Exclusive mutex for Update method to prevent concurrent execution of
+	// Update() and CheckTx(), as per the Mempool interface. The Reap
+	// methods do not take it.
+	updateMtx sync.RWMutex
+	preCheck  PreCheckFunc
+	postCheck PostCheckFunc
+
+	proxyAppConn proxy.AppConnMempool
+
+	// Keeps track of the rechecking process.
+	recheck recheck
+
+	txs    *clist.CList // concurrent linked-list of good txs, in arrival order
+	txsMap sync.Map     // txKey -> *clist.CElement
+
+	// pendingTxInfo carries the TxInfo passed to CheckTx across to the
+	// corresponding globalCb/resCbFirstTime call, since the ABCI response
+	// itself has no room for it. Entries are removed as soon as the
+	// response for that tx is processed.
+	pendingTxInfo sync.Map // txKey -> TxInfo
+
+	// pendingRespCb carries the optional respCb passed to CheckTx across to
+	// the corresponding resCbFirstTime call, same lifecycle as
+	// pendingTxInfo.
+	pendingRespCb sync.Map // txKey -> func(*abci.CheckTxResponse)
+
+	// priorityMtx guards priorityIdx, the secondary ordering index kept
+	// alongside txs. priorityFn and minPriorityBump are set once at
+	// construction time and read without the lock thereafter.
+	priorityMtx     sync.Mutex
+	priorityIdx     priorityQueue
+	priorityFn      PriorityFunc
+	minPriorityBump int64
+
+	logger  log.Logger
+	metrics *Metrics
+}
+
+// recheckReapSharedState bundles the bookkeeping that is shared between an
+// in-flight Update and any number of concurrent SnapshotReap readers.
+//
+// reapRefCount used to be a bool (isReaping) that a single Reap call held
+// for its duration and Update had to wait out, which is what made a second
+// concurrent Reap panic. It is now a refcount: every SnapshotReap and
+// ReapMaxGas call bumps it on entry and drops it on return, so any number
+// of reaps can be in progress at once. Update does not consult it; removals
+// are deferred per entry by mempoolTx.refCount instead.
+type recheckReapSharedState struct {
+	mtx sync.Mutex
+
+	reapRefCount int32
+
+	// Zeroed by reset on every Update; nothing else in this package sets
+	// or reads them.
+	successfullyUpdatedTxs int64
+	bytesUpdated           int64
+	gasUpdated             int64
+
+	// connErr records the last error reported by submitRecheck's errCb, so
+	// a proxy app connection that starts failing recheck enqueues is
+	// surfaced instead of silently wedging the recheck pass.
+	connErr error
+}
+
+func (s *recheckReapSharedState) beginReap() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.reapRefCount++
+}
+
+func (s *recheckReapSharedState) endReap() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.reapRefCount--
+}
+
+func (s *recheckReapSharedState) isReaping() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.reapRefCount > 0
+}
+
+func (s *recheckReapSharedState) reset() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.successfullyUpdatedTxs = 0
+	s.bytesUpdated = 0
+	s.gasUpdated = 0
+	s.connErr = nil
+}
+
+// ConnError returns the last error reported by submitRecheck's errCb, if
+// any, since the shared state was last reset by Update.
+func (s *recheckReapSharedState) ConnError() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.connErr
+}
+
+func (s *recheckReapSharedState) setConnError(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.connErr = err
+}
+
+// recheck holds the cursor state for the recheck-after-Update pass.
+//
+// pendingResponses and doneCh let Update wait out a round before starting
+// the next one: without that, a stale CHECK_TX_TYPE_RECHECK response for
+// tx X from round N could arrive - on the proxy app connection's own
+// callback goroutine, which never holds updateMtx - while Update(N+1) is
+// concurrently removing X because it was just committed, and both sides
+// would call removeTx(X) unsynchronized with each other.
+type recheck struct {
+	cursor *clist.CElement // next entry to recheck
+	end    *clist.CElement // last entry in the mempool at the time recheck started
+
+	// mtx (embedded from recheckReapSharedState) guards pendingResponses
+	// and doneCh too, since they are written from the proxy app callback
+	// goroutine as well as from Update.
+	pendingResponses int32
+	doneCh           chan struct{}
+
+	recheckReapSharedState
+}
+
+func (rc *recheck) done() bool {
+	return rc.cursor == nil
+}
+
+func (rc *recheck) setNextEntry() {
+	if rc.cursor == rc.end {
+		rc.cursor = nil
+		return
+	}
+	if next := rc.cursor.Next(); next != nil {
+		rc.cursor = next
+	} else {
+		rc.cursor = nil
+	}
+}
+
+// startRound (re-)arms doneCh for a round submitting n rechecks. Called
+// only from recheckTxs, which only ever runs from Update.
+func (rc *recheck) startRound(n int32) {
+	rc.mtx.Lock()
+	defer rc.mtx.Unlock()
+	rc.pendingResponses = n
+	if n == 0 {
+		rc.doneCh = nil
+		return
+	}
+	rc.doneCh = make(chan struct{})
+}
+
+// responseReceived accounts for one recheck response - or an enqueue
+// failure that will never produce one - closing doneCh once the round's
+// last one has been accounted for.
+func (rc *recheck) responseReceived() {
+	rc.mtx.Lock()
+	defer rc.mtx.Unlock()
+	if rc.pendingResponses == 0 {
+		return
+	}
+	rc.pendingResponses--
+	if rc.pendingResponses == 0 && rc.doneCh != nil {
+		close(rc.doneCh)
+		rc.doneCh = nil
+	}
+}
+
+// awaitPreviousRound blocks until every recheck response from the prior
+// round, if one is still outstanding, has been accounted for.
+func (rc *recheck) awaitPreviousRound() {
+	rc.mtx.Lock()
+	doneCh := rc.doneCh
+	rc.mtx.Unlock()
+	if doneCh != nil {
+		<-doneCh
+	}
+}
+
+// CListMempoolOption sets an optional parameter on the CListMempool.
+type CListMempoolOption func(*CListMempool)
+
+// WithPreCheck sets a filter for the mempool to reject a tx if f(tx) returns
+// an error. This is ran before CheckTx.
+func WithPreCheck(f PreCheckFunc) CListMempoolOption {
+	return func(mem *CListMempool) { mem.preCheck = f }
+}
+
+// WithPostCheck sets a filter for the mempool to reject a tx if f(tx) returns
+// an error. This is ran after CheckTx.
+func WithPostCheck(f PostCheckFunc) CListMempoolOption {
+	return func(mem *CListMempool) { mem.postCheck = f }
+}
+
+// WithMetrics sets the metrics.
+func WithMetrics(metrics *Metrics) CListMempoolOption {
+	return func(mem *CListMempool) { mem.metrics = metrics }
+}
+
+// WithPriorityFunc overrides the default GasWanted-per-byte priority used
+// to order ReapMaxGas and to decide which tx to evict when the mempool is
+// full.
+func WithPriorityFunc(f PriorityFunc) CListMempoolOption {
+	return func(mem *CListMempool) { mem.priorityFn = f }
+}
+
+// WithMinPriorityBump sets how much higher an incoming tx's priority must
+// be over the current lowest-priority entry before it is allowed to evict
+// it when the mempool is full. The default is 0, meaning any strictly
+// higher priority evicts.
+func WithMinPriorityBump(bump int64) CListMempoolOption {
+	return func(mem *CListMempool) { mem.minPriorityBump = bump }
+}
+
+// NewCListMempool returns a new mempool with the given configuration and
+// connection to an application.
+func NewCListMempool(
+	cfg *config.MempoolConfig,
+	proxyAppConn proxy.AppConnMempool,
+	height int64,
+	options ...CListMempoolOption,
+) *CListMempool {
+	mp := &CListMempool{
+		config:       cfg,
+		proxyAppConn: proxyAppConn,
+		txs:          clist.New(),
+		priorityFn:   defaultPriority,
+		logger:       log.NewNopLogger(),
+		metrics:      NopMetrics(),
+	}
+	mp.height.Store(height)
+
+	for _, option := range options {
+		option(mp)
+	}
+
+	proxyAppConn.SetResponseCallback(mp.globalCb)
+
+	return mp
+}
+
+func (mem *CListMempool) SetLogger(l log.Logger) {
+	mem.logger = l
+}
+
+func (mem *CListMempool) Lock() {
+	mem.updateMtx.Lock()
+}
+
+func (mem *CListMempool) Unlock() {
+	mem.updateMtx.Unlock()
+}
+
+func (mem *CListMempool) Size() int {
+	return mem.txs.Len()
+}
+
+func (mem *CListMempool) SizeBytes() int64 {
+	return mem.txsBytes.Load()
+}
+
+// TxsFront returns the first element of mem.txs, for peer broadcast loops
+// that walk the CList directly.
+func (mem *CListMempool) TxsFront() *clist.CElement {
+	return mem.txs.Front()
+}
+
+// TxsWaitChan returns a channel that closes once the mempool becomes
+// non-empty, for broadcast loops waiting on TxsFront to become available.
+func (mem *CListMempool) TxsWaitChan() <-chan struct{} {
+	return mem.txs.WaitChan()
+}
+
+func (mem *CListMempool) FlushAppConn() error {
+	return mem.proxyAppConn.Flush(context.TODO())
+}
+
+func (mem *CListMempool) TxsAvailable() <-chan struct{} {
+	return mem.txsAvailable
+}
+
+func (mem *CListMempool) EnableTxsAvailable() {
+	mem.txsAvailable = make(chan struct{}, 1)
+}
+
+// notifyTxsAvailable performs the at-most-once-per-height send on
+// txsAvailable promised by TxsAvailable/EnableTxsAvailable: the first call
+// since the most recent Update reset notifiedTxsAvailable - from a
+// successful CheckTx, or from Update itself when txs survive it - that
+// finds the mempool non-empty fires it; later calls for the same height
+// are no-ops. The send is
+// non-blocking since txsAvailable is buffered with capacity 1 and nobody
+// but this method ever sends on it.
+func (mem *CListMempool) notifyTxsAvailable() {
+	if mem.txsAvailable == nil {
+		return
+	}
+	if mem.Size() == 0 {
+		return
+	}
+	if !mem.notifiedTxsAvailable.CompareAndSwap(false, true) {
+		return
+	}
+	select {
+	case mem.txsAvailable <- struct{}{}:
+	default:
+	}
+}
+
+func (mem *CListMempool) Flush() {
+	mem.updateMtx.RLock()
+	defer mem.updateMtx.RUnlock()
+
+	mem.txsBytes.Store(0)
+
+	for e := mem.txs.Front(); e != nil; e = e.Next() {
+		mem.txs.Remove(e)
+		e.DetachPrev()
+	}
+	mem.txsMap.Range(func(key, _ interface{}) bool {
+		mem.txsMap.Delete(key)
+		return true
+	})
+
+	mem.priorityMtx.Lock()
+	mem.priorityIdx = priorityQueue{}
+	mem.priorityMtx.Unlock()
+}
+
+// ReapSnapshot is an immutable, point-in-time view over the mempool's CList
+// produced by SnapshotReap, on which ReapMaxTxs and ReapMaxBytesMaxGas are
+// built. Obtaining a snapshot never blocks behind, or conflicts with, an
+// in-progress Update:
+// every entry the snapshot walks has had its refcount bumped, so Update's
+// removal path leaves the entry's memory intact (just unlinked from the
+// live CList) until the last snapshot referencing it is Close()'d.
+type ReapSnapshot struct {
+	mem     *CListMempool
+	entries []*clist.CElement
+	txs     []types.Tx
+	closed  bool
+}
+
+// Txs returns the transactions captured by the snapshot, in CList
+// (arrival) order.
+func (s *ReapSnapshot) Txs() []types.Tx {
+	return s.txs
+}
+
+// Close releases the refcount this snapshot holds on every entry it
+// pinned. It is safe, but unnecessary, to call Close more than once.
+func (s *ReapSnapshot) Close() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	for _, e := range s.entries {
+		memTx := e.Value.(*mempoolTx)
+		memTx.unref()
+		s.mem.maybeFinalizeRemoval(e)
+	}
+}
+
+// SnapshotReap returns an immutable cursor over up to maxTxs transactions
+// (or maxBytes/maxGas worth of them, whichever limit is hit first) without
+// taking an exclusive lock against concurrent Update calls or other
+// concurrent SnapshotReap callers. Pass a non-positive value for a limit to
+// leave it unbounded.
+//
+// The caller must Close() the returned snapshot once done with it so that
+// entries removed by a concurrent Update can be freed.
+func (mem *CListMempool) SnapshotReap(maxTxs, maxBytes, maxGas int64) *ReapSnapshot {
+	mem.recheck.beginReap()
+	defer mem.recheck.endReap()
+
+	var (
+		totalBytes int64
+		totalGas   int64
+		entries    []*clist.CElement
+		txs        []types.Tx
+	)
+
+	for e := mem.txs.Front(); e != nil; e = e.Next() {
+		if maxTxs > 0 && int64(len(txs)) >= maxTxs {
+			break
+		}
+		memTx := e.Value.(*mempoolTx)
+
+		dataLen := int64(len(memTx.tx))
+		if maxBytes > 0 && totalBytes+dataLen > maxBytes {
+			break
+		}
+		newTotalGas := totalGas + memTx.gasWanted
+		if maxGas > 0 && newTotalGas > maxGas {
+			break
+		}
+		totalBytes += dataLen
+		totalGas = newTotalGas
+
+		memTx.ref()
+		entries = append(entries, e)
+		txs = append(txs, memTx.tx)
+	}
+
+	return &ReapSnapshot{mem: mem, entries: entries, txs: txs}
+}
+
+// ReapMaxBytesMaxGas reaps transactions from the mempool up to maxBytes
+// bytes total with the condition that the total gasWanted must not exceed
+// maxGas. As in SnapshotReap, a non-positive maxBytes or maxGas leaves that
+// limit unbounded. It never blocks behind, or panics on, a concurrent
+// Update: it is implemented in terms of SnapshotReap.
+func (mem *CListMempool) ReapMaxBytesMaxGas(maxBytes, maxGas int64) types.Txs {
+	snapshot := mem.SnapshotReap(-1, maxBytes, maxGas)
+	defer snapshot.Close()
+	return snapshot.Txs()
+}
+
+// ReapMaxTxs reaps up to max transactions from the mempool. If max is
+// negative, there is no cap on the number of returned transactions.
+func (mem *CListMempool) ReapMaxTxs(max int) types.Txs {
+	if max == 0 {
+		return types.Txs{}
+	}
+	snapshot := mem.SnapshotReap(int64(max), -1, -1)
+	defer snapshot.Close()
+	return snapshot.Txs()
+}
+
+// maybeFinalizeRemoval releases an entry's txsMap slot and byte accounting
+// once both (a) Update has unlinked it from the live CList and (b) no open
+// ReapSnapshot still references it. It is safe to call from either side
+// (removeTx or ReapSnapshot.Close) in any order: the CAS on memTx.finalized
+// ensures exactly one of the racing callers actually does the work.
+func (mem *CListMempool) maybeFinalizeRemoval(e *clist.CElement) {
+	memTx := e.Value.(*mempoolTx)
+	if atomic.LoadInt32(&memTx.refCount) != 0 {
+		return
+	}
+	if atomic.LoadInt32(&memTx.pendingRemoval) == 0 {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&memTx.finalized, 0, 1) {
+		return
+	}
+	mem.txsMap.Delete(memTx.tx.Key())
+	mem.txsBytes.Add(-int64(len(memTx.tx)))
+}
+
+// removeTx unlinks e from the live CList so that no new reader will
+// observe it, then hands it off to maybeFinalizeRemoval: if a concurrent
+// ReapSnapshot still pins the entry, the final cleanup happens when that
+// snapshot is Close()'d instead of here.
+//
+// The CAS on memTx.pendingRemoval makes this safe to call more than once
+// for the same entry from independent, unsynchronized callers - e.g. a
+// freshly-arrived CheckTx response evicting tx X via tryEvict at the same
+// time Update is removing X because it was just committed. Whichever call
+// wins the CAS does the actual unlink; the other is a no-op instead of
+// double-removing e from the CList, which would panic.
+func (mem *CListMempool) removeTx(e *clist.CElement) {
+	memTx := e.Value.(*mempoolTx)
+	if !atomic.CompareAndSwapInt32(&memTx.pendingRemoval, 0, 1) {
+		return
+	}
+
+	mem.txs.Remove(e)
+	e.DetachPrev()
+
+	mem.priorityMtx.Lock()
+	heap.Remove(&mem.priorityIdx, memTx.heapIndex)
+	mem.priorityMtx.Unlock()
+
+	mem.maybeFinalizeRemoval(e)
+}
+
+// RemoveTxByKey removes a transaction, identified by its key, from the
+// mempool. It returns ErrTxNotFound if no such transaction is tracked.
+func (mem *CListMempool) RemoveTxByKey(txKey types.TxKey) error {
+	e, ok := mem.txsMap.Load(txKey)
+	if !ok {
+		return ErrTxNotFound
+	}
+	mem.removeTx(e.(*clist.CElement))
+	return nil
+}
+
+// isFull returns an ErrMempoolIsFull if adding a tx of txSize bytes would
+// put the mempool over its configured tx-count or byte-size limits.
+func (mem *CListMempool) isFull(txSize int) error {
+	var (
+		numTxs   = mem.Size()
+		txsBytes = mem.SizeBytes()
+	)
+	if numTxs >= mem.config.Size || int64(txSize)+txsBytes > mem.config.MaxTxsBytes {
+		return ErrMempoolIsFull{
+			NumTxs:      numTxs,
+			MaxTxs:      mem.config.Size,
+			TxsBytes:    txsBytes,
+			MaxTxsBytes: mem.config.MaxTxsBytes,
+		}
+	}
+	return nil
+}
+
+// tryEvict makes room for a tx with the given incoming priority by
+// dropping the current lowest-priority entry, provided incoming is
+// strictly higher and at least minPriorityBump higher. It reports whether
+// an entry was evicted.
+func (mem *CListMempool) tryEvict(incoming int64) bool {
+	mem.priorityMtx.Lock()
+	victim := mem.priorityIdx.lowestPriority()
+	if victim == nil {
+		mem.priorityMtx.Unlock()
+		return false
+	}
+	victimPriority := victim.Value.(*mempoolTx).priority
+	mem.priorityMtx.Unlock()
+	if incoming <= victimPriority || incoming < victimPriority+mem.minPriorityBump {
+		return false
+	}
+
+	mem.removeTx(victim)
+	return true
+}
+
+// addTx links memTx into the arrival-ordered CList, the txKey lookup map,
+// and the priority index, and accounts for its size.
+func (mem *CListMempool) addTx(memTx *mempoolTx) *clist.CElement {
+	e := mem.txs.PushBack(memTx)
+	mem.txsMap.Store(memTx.tx.Key(), e)
+	mem.txsBytes.Add(int64(len(memTx.tx)))
+
+	mem.priorityMtx.Lock()
+	heap.Push(&mem.priorityIdx, e)
+	mem.priorityMtx.Unlock()
+
+	return e
+}
+
+// CheckTx executes a new transaction against the application to determine
+// its validity and whether it should be added to the mempool. If tx is
+// already in the mempool, txInfo.SenderID is recorded against the existing
+// entry (so the reactor won't gossip the tx back to it) instead of the tx
+// being silently dropped.
+//
+// errCb fires if the proxy app connection itself fails to enqueue the
+// request (e.g. the connection is down); respCb fires once the
+// application's CheckTx response arrives, via the resCbFirstTime path.
+// At most one of the two is called for a given invocation; neither is
+// called when tx is rejected before reaching the application (a
+// PreCheckFunc failure or ErrTxInCache). Either may be nil.
+func (mem *CListMempool) CheckTx(
+	tx types.Tx,
+	txInfo TxInfo,
+	errCb func(error),
+	respCb func(*abci.CheckTxResponse),
+) error {
+	mem.updateMtx.RLock()
+	defer mem.updateMtx.RUnlock()
+
+	if mem.preCheck != nil {
+		if err := mem.preCheck(tx); err != nil {
+			return ErrPreCheck{Reason: err}
+		}
+	}
+
+	txKey := tx.Key()
+	if e, loaded := mem.txsMap.Load(txKey); loaded {
+		memTx := e.(*clist.CElement).Value.(*mempoolTx)
+		memTx.addSender(txInfo.SenderID)
+		return ErrTxInCache
+	}
+
+	mem.pendingTxInfo.Store(txKey, txInfo)
+	if respCb != nil {
+		mem.pendingRespCb.Store(txKey, respCb)
+	}
+
+	if _, err := mem.proxyAppConn.CheckTxAsync(context.TODO(), &abci.CheckTxRequest{
+		Tx:   tx,
+		Type: abci.CHECK_TX_TYPE_CHECK,
+	}); err != nil {
+		mem.pendingTxInfo.Delete(txKey)
+		mem.pendingRespCb.Delete(txKey)
+		if errCb != nil {
+			errCb(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// CheckTxSync is a thin compatibility shim over CheckTx for callers that
+// only need to know whether the app accepted tx for checking, not its
+// eventual CheckTx response.
+func (mem *CListMempool) CheckTxSync(tx types.Tx, txInfo TxInfo) error {
+	return mem.CheckTx(tx, txInfo, nil, nil)
+}
+
+// globalCb is registered once, in NewCListMempool, as the proxy app
+// connection's response callback. It demultiplexes every CheckTx response
+// - both first-time checks and rechecks issued during Update - to the
+// appropriate handler.
+func (mem *CListMempool) globalCb(req *abci.Request, res *abci.Response) {
+	checkTxReq := req.GetCheckTx()
+	checkTxRes := res.GetCheckTx()
+	if checkTxReq == nil || checkTxRes == nil {
+		return
+	}
+
+	tx := types.Tx(checkTxReq.Tx)
+	if checkTxReq.Type == abci.CHECK_TX_TYPE_RECHECK {
+		mem.resCbRecheck(tx, checkTxRes)
+		return
+	}
+	mem.resCbFirstTime(tx, checkTxRes)
+}
+
+// resCbFirstTime handles the CheckTx response for a transaction that has
+// never been in the mempool before. If the mempool is full, it makes one
+// attempt to evict the current lowest-priority entry in favor of tx before
+// giving up on it.
+func (mem *CListMempool) resCbFirstTime(tx types.Tx, res *abci.CheckTxResponse) {
+	txKey := tx.Key()
+	var txInfo TxInfo
+	if v, ok := mem.pendingTxInfo.LoadAndDelete(txKey); ok {
+		txInfo = v.(TxInfo)
+	}
+	if v, ok := mem.pendingRespCb.LoadAndDelete(txKey); ok {
+		v.(func(*abci.CheckTxResponse))(res)
+	}
+
+	if res.Code != abci.CodeTypeOK {
+		return
+	}
+
+	priority := mem.priorityFn(tx, res)
+
+	if err := mem.isFull(len(tx)); err != nil {
+		if !mem.tryEvict(priority) {
+			return
+		}
+	}
+
+	if mem.postCheck != nil {
+		if err := mem.postCheck(tx, res); err != nil {
+			return
+		}
+	}
+
+	memTx := &mempoolTx{
+		height:    mem.height.Load(),
+		gasWanted: res.GasWanted,
+		tx:        tx,
+		priority:  priority,
+	}
+	memTx.addSender(txInfo.SenderID)
+	mem.addTx(memTx)
+	mem.notifyTxsAvailable()
+
+	if mem.metrics != nil && mem.metrics.Size != nil {
+		mem.metrics.Size(mem.Size())
+	}
+}
+
+// resCbRecheck handles the CheckTx response for a transaction that Update
+// resubmitted for rechecking. A non-OK code removes the entry; an OK code
+// refreshes its priority, since GasWanted (and thus a GasWanted-per-byte
+// PriorityFunc) can change between checks.
+//
+// It always calls recheck.responseReceived(), even when the tx is no
+// longer tracked (e.g. it was already committed), so the next Update's
+// awaitPreviousRound doesn't wait forever on a response that was accounted
+// for by removal rather than by arriving here.
+func (mem *CListMempool) resCbRecheck(tx types.Tx, res *abci.CheckTxResponse) {
+	defer mem.recheck.responseReceived()
+
+	v, ok := mem.txsMap.Load(tx.Key())
+	if !ok {
+		return
+	}
+	e := v.(*clist.CElement)
+
+	if res.Code != abci.CodeTypeOK {
+		mem.removeTx(e)
+		return
+	}
+
+	memTx := e.Value.(*mempoolTx)
+	newPriority := mem.priorityFn(tx, res)
+
+	mem.priorityMtx.Lock()
+	memTx.priority = newPriority
+	heap.Fix(&mem.priorityIdx, memTx.heapIndex)
+	mem.priorityMtx.Unlock()
+}
+
+// ReapMaxGas returns transactions from the priority index in descending
+// priority order (see PriorityFunc) up to maxGas total GasWanted. A
+// non-positive maxGas leaves the result unbounded. Unlike ReapMaxTxs and
+// ReapMaxBytesMaxGas, this does not walk the CList, so it reflects
+// priorities as of the moment it snapshots the index rather than arrival
+// order.
+func (mem *CListMempool) ReapMaxGas(maxGas int64) types.Txs {
+	mem.recheck.beginReap()
+	defer mem.recheck.endReap()
+
+	// Snapshot the fields we need while priorityMtx is held: priority is
+	// guarded by priorityMtx (clist_mempool.go:36) and resCbRecheck can be
+	// mutating it concurrently, so nothing below may dereference memTx
+	// after the unlock.
+	type reapCandidate struct {
+		priority  int64
+		gasWanted int64
+		tx        types.Tx
+	}
+
+	mem.priorityMtx.Lock()
+	candidates := make([]reapCandidate, len(mem.priorityIdx.items))
+	for i, e := range mem.priorityIdx.items {
+		memTx := e.Value.(*mempoolTx)
+		candidates[i] = reapCandidate{priority: memTx.priority, gasWanted: memTx.gasWanted, tx: memTx.tx}
+	}
+	mem.priorityMtx.Unlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].priority > candidates[j].priority
+	})
+
+	var (
+		totalGas int64
+		txs      types.Txs
+	)
+	for _, c := range candidates {
+		newTotal := totalGas + c.gasWanted
+		if maxGas > 0 && newTotal > maxGas {
+			break
+		}
+		totalGas = newTotal
+		txs = append(txs, c.tx)
+	}
+	return txs
+}
+
+// submitRecheck resubmits e's tx to the application with
+// CHECK_TX_TYPE_RECHECK. If the proxy app connection fails to enqueue the
+// request, the entry is flagged recheckFailed instead of panicking here:
+// Update picks up that flag on its next call and removes the entry, so a
+// connection that starts failing recheck enqueues doesn't wedge the
+// recheck pass on a dead entry forever. An enqueue failure also counts as
+// the round's response for e, since resCbRecheck will never be called for
+// it otherwise.
+func (mem *CListMempool) submitRecheck(e *clist.CElement) {
+	memTx := e.Value.(*mempoolTx)
+	_, err := mem.proxyAppConn.CheckTxAsync(context.TODO(), &abci.CheckTxRequest{
+		Tx:   memTx.tx,
+		Type: abci.CHECK_TX_TYPE_RECHECK,
+	})
+	if err != nil {
+		mem.markRecheckConnUnhealthy(memTx, err)
+		mem.recheck.responseReceived()
+	}
+}
+
+// markRecheckConnUnhealthy records err on the shared recheck state and
+// flags memTx for removal on the next Update, in place of the panic this
+// path used to hit in the global response callback.
+func (mem *CListMempool) markRecheckConnUnhealthy(memTx *mempoolTx, err error) {
+	mem.logger.Error("recheck enqueue failed, marking connection unhealthy", "err", err)
+	mem.recheck.setConnError(err)
+	atomic.StoreInt32(&memTx.recheckFailed, 1)
+}
+
+// recheckTxs resubmits every tx currently in the mempool for rechecking,
+// if enabled. It is only ever called from Update, which runs exclusively
+// with respect to CheckTx (both require updateMtx), and only once the
+// caller has confirmed (via awaitPreviousRound) that the previous round's
+// responses have all been accounted for.
+func (mem *CListMempool) recheckTxs() {
+	if !mem.config.Recheck || mem.txs.Len() == 0 {
+		mem.recheck.startRound(0)
+		return
+	}
+
+	mem.recheck.cursor = mem.txs.Front()
+	mem.recheck.end = mem.txs.Back()
+
+	// Bound the count by end, the tail at the time recheck started: addTx
+	// can append new txs to mem.txs without holding updateMtx, and a tx
+	// landing after end must be excluded from both this count and the
+	// submission loop below, or the round's doneCh would close before an
+	// extra, uncounted response comes back. It is picked up by the next
+	// Update's round instead.
+	var n int32
+	for e := mem.recheck.cursor; e != nil; e = e.Next() {
+		n++
+		if e == mem.recheck.end {
+			break
+		}
+	}
+	// Arm doneCh before submitting anything, since a synchronous (mock)
+	// proxy app connection can call back into resCbRecheck before
+	// CheckTxAsync even returns.
+	mem.recheck.startRound(n)
+
+	for !mem.recheck.done() {
+		mem.submitRecheck(mem.recheck.cursor)
+		mem.recheck.setNextEntry()
+	}
+}
+
+// Update informs the mempool that the given txs were committed and can be
+// discarded.
+//
+// NOTE:
+//   - Update removes blockTxs from the mempool itself; the caller must
+//     hold Lock() for the duration of the call.
+func (mem *CListMempool) Update(
+	blockHeight int64,
+	blockTxs types.Txs,
+	_ []*abci.ExecTxResult,
+	newPreFn PreCheckFunc,
+	newPostFn PostCheckFunc,
+) error {
+	// Wait out any recheck responses still in flight from the previous
+	// Update's round before touching the CList or txsMap below: otherwise
+	// a stale resCbRecheck call for a tx this Update is about to remove
+	// (because it was just committed) could race this removal and panic.
+	mem.recheck.awaitPreviousRound()
+
+	mem.height.Store(blockHeight)
+	mem.notifiedTxsAvailable.Store(false)
+
+	if newPreFn != nil {
+		mem.preCheck = newPreFn
+	}
+	if newPostFn != nil {
+		mem.postCheck = newPostFn
+	}
+
+	for _, tx := range blockTxs {
+		if e, ok := mem.txsMap.Load(tx.Key()); ok {
+			mem.removeTx(e.(*clist.CElement))
+		}
+	}
+
+	// Drop any entry a previous recheck pass couldn't re-enqueue, rather
+	// than leaving recheck.cursor wedged on it forever.
+	for e := mem.txs.Front(); e != nil; e = e.Next() {
+		if atomic.LoadInt32(&e.Value.(*mempoolTx).recheckFailed) == 1 {
+			mem.removeTx(e)
+		}
+	}
+
+	mem.recheck.reset()
+	mem.recheckTxs()
+
+	// Surviving txs (e.g. from a byte/gas-limited or intentionally empty
+	// block) need to re-arm TxsAvailable themselves: nothing else signals
+	// consensus for the next height if no new CheckTx ever arrives.
+	mem.notifyTxsAvailable()
+
+	return nil
+}
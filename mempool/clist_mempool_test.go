@@ -0,0 +1,68 @@
+package mempool
+
+import (
+	"crypto/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	abciclient "github.com/cometbft/cometbft/abci/client"
+	abciclimocks "github.com/cometbft/cometbft/abci/client/mocks"
+	"github.com/cometbft/cometbft/config"
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cometbft/cometbft/proxy"
+	"github.com/cometbft/cometbft/types"
+)
+
+// newMempoolWithAppAndConfigMock wires up a CListMempool against appConnMem
+// (typically a mocked abciclient.Client), ready to use. The returned func
+// cleans up the config's temporary root directory.
+func newMempoolWithAppAndConfigMock(cfg *config.Config, appConnMem abciclient.Client) (*CListMempool, func()) {
+	appConnMem.SetLogger(log.NewNopLogger())
+	if err := appConnMem.Start(); err != nil {
+		panic(err)
+	}
+
+	mp := NewCListMempool(cfg.Mempool, proxy.NewAppConnMempool(appConnMem, proxy.NopMetrics()), 0)
+	mp.SetLogger(log.NewNopLogger())
+
+	return mp, func() { os.RemoveAll(cfg.RootDir) }
+}
+
+// checkTxs submits count distinct txs to mp and returns them in submission
+// order. It fails the test immediately if any of them is rejected.
+func checkTxs(t *testing.T, mp *CListMempool, count int) types.Txs {
+	t.Helper()
+
+	txs := make(types.Txs, count)
+	for i := 0; i < count; i++ {
+		txBytes := make([]byte, 20)
+		_, err := rand.Read(txBytes)
+		require.NoError(t, err)
+
+		txs[i] = txBytes
+		require.NoError(t, mp.CheckTx(txBytes, TxInfo{}, nil, nil))
+	}
+	return txs
+}
+
+// newMockABCIClient returns an abciclimocks.Client with the
+// Start/SetLogger/SetResponseCallback/Error/Flush scaffolding every
+// mempool test needs already wired up, plus a pointer to the callback
+// registered via SetResponseCallback so a caller's own CheckTxAsync
+// expectation can invoke it. Callers are responsible for setting up that
+// CheckTxAsync expectation themselves.
+func newMockABCIClient() (*abciclimocks.Client, *abciclient.Callback) {
+	var callback abciclient.Callback
+
+	mockClient := new(abciclimocks.Client)
+	mockClient.On("Start").Return(nil)
+	mockClient.On("SetLogger", mock.Anything)
+	mockClient.On("SetResponseCallback", mock.MatchedBy(func(cb abciclient.Callback) bool { callback = cb; return true }))
+	mockClient.On("Error").Return(nil)
+	mockClient.On("Flush", mock.Anything).Return(nil)
+
+	return mockClient, &callback
+}
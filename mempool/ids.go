@@ -0,0 +1,88 @@
+package mempool
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/cometbft/cometbft/p2p"
+)
+
+// UnknownPeerID is the peer ID used when a tx did not arrive from a p2p
+// peer, e.g. one submitted directly over RPC. It is reserved and is never
+// handed out by mempoolIDs.ReserveForPeer.
+const UnknownPeerID uint16 = 0
+
+// maxActiveIDs is the upper bound on how many peers can be simultaneously
+// tracked, since peer identifiers are packed into a uint16 to keep each
+// mempool entry's sender set small.
+const maxActiveIDs = math.MaxUint16
+
+// mempoolIDs maintains a bidirectional mapping between a peer's p2p.ID and
+// a compact uint16 identifier. Tracking senders by this small integer,
+// rather than the full p2p.ID, is what makes it cheap to keep a sender set
+// on every mempool entry.
+type mempoolIDs struct {
+	mtx       sync.RWMutex
+	peerMap   map[p2p.ID]uint16
+	nextID    uint16
+	activeIDs map[uint16]struct{}
+}
+
+func newMempoolIDs() *mempoolIDs {
+	return &mempoolIDs{
+		peerMap:   make(map[p2p.ID]uint16),
+		activeIDs: map[uint16]struct{}{UnknownPeerID: {}},
+		nextID:    UnknownPeerID + 1,
+	}
+}
+
+// ReserveForPeer assigns and returns a uint16 identifier for peer, to be
+// used as the SenderID on TxInfo for anything received from it.
+func (ids *mempoolIDs) ReserveForPeer(peer p2p.Peer) uint16 {
+	ids.mtx.Lock()
+	defer ids.mtx.Unlock()
+
+	id := ids.nextPeerID()
+	ids.peerMap[peer.ID()] = id
+	ids.activeIDs[id] = struct{}{}
+	return id
+}
+
+// Reclaim releases the identifier reserved for peer so it can be reused,
+// e.g. once the peer disconnects.
+func (ids *mempoolIDs) Reclaim(peer p2p.Peer) {
+	ids.mtx.Lock()
+	defer ids.mtx.Unlock()
+
+	id, ok := ids.peerMap[peer.ID()]
+	if !ok {
+		return
+	}
+	delete(ids.activeIDs, id)
+	delete(ids.peerMap, peer.ID())
+}
+
+// GetForPeer returns peer's previously reserved identifier, or
+// UnknownPeerID if none was reserved (e.g. Reclaim already ran).
+func (ids *mempoolIDs) GetForPeer(peer p2p.Peer) uint16 {
+	ids.mtx.RLock()
+	defer ids.mtx.RUnlock()
+	return ids.peerMap[peer.ID()]
+}
+
+// nextPeerID must be called with ids.mtx held.
+func (ids *mempoolIDs) nextPeerID() uint16 {
+	if len(ids.activeIDs) >= maxActiveIDs {
+		panic(fmt.Sprintf("node has maximum %d active IDs and wanted to get one more", maxActiveIDs))
+	}
+
+	_, idExists := ids.activeIDs[ids.nextID]
+	for idExists {
+		ids.nextID++
+		_, idExists = ids.activeIDs[ids.nextID]
+	}
+	id := ids.nextID
+	ids.nextID++
+	return id
+}
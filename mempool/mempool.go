@@ -0,0 +1,176 @@
+package mempool
+
+import (
+	"errors"
+	"fmt"
+
+	abci "github.com/cometbft/cometbft/api/cometbft/abci/v1"
+	"github.com/cometbft/cometbft/p2p"
+	"github.com/cometbft/cometbft/types"
+)
+
+// Mempool defines the boilerplate interface that all mempool
+// implementations must satisfy. This interface does not mandate any
+// concrete implementation details, but rather specifies how the
+// ABCI-app/reactor and other upstream logic should interact with the
+// mempool.
+type Mempool interface {
+	// CheckTx executes a new transaction against the application to
+	// determine its validity and whether it should be added to the
+	// mempool. txInfo carries the tx's origin, so that a duplicate
+	// arriving from a different peer than the one already tracked is
+	// recorded rather than just rejected.
+	//
+	// errCb fires if the proxy app connection itself fails to enqueue the
+	// request (e.g. the connection is down); respCb fires once the
+	// application's CheckTx response arrives. At most one of the two is
+	// called for a given invocation; neither is called when tx is rejected
+	// before reaching the application. Either may be nil.
+	CheckTx(tx types.Tx, txInfo TxInfo, errCb func(error), respCb func(*abci.CheckTxResponse)) error
+
+	// RemoveTxByKey removes a transaction, identified by its key,
+	// from the mempool.
+	RemoveTxByKey(txKey types.TxKey) error
+
+	// ReapMaxBytesMaxGas reaps transactions from the mempool up to maxBytes
+	// bytes total with the condition that the total gasWanted must not
+	// exceed maxGas.
+	ReapMaxBytesMaxGas(maxBytes, maxGas int64) types.Txs
+
+	// ReapMaxTxs reaps up to max transactions from the mempool. If max is
+	// negative, there is no cap on the number of returned transactions.
+	ReapMaxTxs(max int) types.Txs
+
+	// Lock locks the mempool. The consensus must be able to hold lock to
+	// avoid races.
+	Lock()
+
+	// Unlock unlocks the mempool.
+	Unlock()
+
+	// Update informs the mempool that the given txs were committed and can
+	// be discarded. Non-nil newPreFn/newPostFn replace the current filters
+	// for later CheckTx calls; if rechecking is enabled, the remaining
+	// transactions are resubmitted to the application.
+	Update(
+		blockHeight int64,
+		blockTxs types.Txs,
+		deliverTxResponses []*abci.ExecTxResult,
+		newPreFn PreCheckFunc,
+		newPostFn PostCheckFunc,
+	) error
+
+	// FlushAppConn flushes the mempool connection to ensure async callback
+	// calls are done, e.g. from CheckTx.
+	FlushAppConn() error
+
+	// Flush removes all transactions from the mempool and cache.
+	Flush()
+
+	// TxsAvailable returns a channel which fires once for every height, and
+	// only when transactions are available in the mempool.
+	TxsAvailable() <-chan struct{}
+
+	// EnableTxsAvailable initializes the TxsAvailable channel, ensuring it
+	// will trigger once every height when transactions are available.
+	EnableTxsAvailable()
+
+	// Size returns the number of transactions in the mempool.
+	Size() int
+
+	// SizeBytes returns the total size of all txs in the mempool.
+	SizeBytes() int64
+}
+
+// TxInfo are parameters that accompany a tx as it is added to the mempool,
+// describing where it came from.
+type TxInfo struct {
+	// SenderID is the internal, per-process peer ID assigned by the
+	// reactor's mempoolIDs, used to track which peers have already sent a
+	// given tx so it isn't gossiped back to them. UnknownPeerID
+	// (the zero value) means the tx did not arrive from a peer, e.g. it was
+	// submitted directly over RPC.
+	SenderID uint16
+
+	// SenderP2PID is the sender's actual p2p.ID, kept for logging since
+	// SenderID is only meaningful within this process.
+	SenderP2PID p2p.ID
+}
+
+// PreCheckFunc is an optional filter executed before CheckTx and rejects
+// transactions for which it returns an error.
+type PreCheckFunc func(types.Tx) error
+
+// PostCheckFunc is an optional filter executed after CheckTx and rejects
+// transactions for which it returns an error.
+type PostCheckFunc func(types.Tx, *abci.CheckTxResponse) error
+
+// PreCheckMaxBytes checks that the size of the transaction is smaller or
+// equal to the expected maxBytes.
+func PreCheckMaxBytes(maxBytes int64) PreCheckFunc {
+	return func(tx types.Tx) error {
+		txSize := int64(len(tx))
+		if txSize > maxBytes {
+			return fmt.Errorf("tx size is too big: %d, max: %d", txSize, maxBytes)
+		}
+		return nil
+	}
+}
+
+// PostCheckMaxGas checks that the wanted gas is smaller or equal to the
+// passed maxGas. Returns nil if maxGas is -1.
+func PostCheckMaxGas(maxGas int64) PostCheckFunc {
+	return func(_ types.Tx, res *abci.CheckTxResponse) error {
+		if maxGas == -1 {
+			return nil
+		}
+		if res.GasWanted < 0 {
+			return fmt.Errorf("gas wanted %d is negative", res.GasWanted)
+		}
+		if res.GasWanted > maxGas {
+			return fmt.Errorf("gas wanted %d is greater than max gas %d", res.GasWanted, maxGas)
+		}
+		return nil
+	}
+}
+
+// ErrTxInCache is returned to the client if we saw tx earlier.
+var ErrTxInCache = errors.New("tx already exists in cache")
+
+// ErrTxNotFound is returned by RemoveTxByKey if txKey isn't in the mempool.
+var ErrTxNotFound = errors.New("tx not found in mempool")
+
+// ErrMempoolIsFull means that the mempool is full and has reached its
+// configured capacity limits.
+type ErrMempoolIsFull struct {
+	NumTxs      int
+	MaxTxs      int
+	TxsBytes    int64
+	MaxTxsBytes int64
+}
+
+func (e ErrMempoolIsFull) Error() string {
+	return fmt.Sprintf(
+		"mempool is full: number of txs %d (max: %d), total txs bytes %d (max: %d)",
+		e.NumTxs, e.MaxTxs, e.TxsBytes, e.MaxTxsBytes,
+	)
+}
+
+// ErrPreCheck is returned when tx is rejected by PreCheckFunc.
+type ErrPreCheck struct {
+	Reason error
+}
+
+func (e ErrPreCheck) Error() string {
+	return e.Reason.Error()
+}
+
+func (e ErrPreCheck) Unwrap() error {
+	return e.Reason
+}
+
+// IsPreCheckError returns true if err is due to a pre-check failure.
+func IsPreCheckError(err error) bool {
+	var e ErrPreCheck
+	return errors.As(err, &e)
+}
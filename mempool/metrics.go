@@ -0,0 +1,16 @@
+package mempool
+
+// Metrics contains the instrumentation exposed by the mempool. It is kept
+// minimal here; see the discard.go-style NopMetrics below for use in tests
+// and other contexts where metrics are not needed.
+type Metrics struct {
+	// Size is the number of transactions currently in the mempool.
+	Size func(int)
+}
+
+// NopMetrics returns a Metrics that discards all observations.
+func NopMetrics() *Metrics {
+	return &Metrics{
+		Size: func(int) {},
+	}
+}
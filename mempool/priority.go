@@ -0,0 +1,81 @@
+package mempool
+
+import (
+	"container/heap"
+
+	abci "github.com/cometbft/cometbft/api/cometbft/abci/v1"
+	"github.com/cometbft/cometbft/internal/clist"
+	"github.com/cometbft/cometbft/types"
+)
+
+// PriorityFunc computes an ordering priority for tx from the application's
+// CheckTx response. Higher-priority transactions are reaped first by
+// ReapMaxGas and are preferred survivors when the mempool is full. The
+// default scores by GasWanted per byte; apps that encode their own
+// priority in the response can override it via WithPriorityFunc.
+type PriorityFunc func(tx types.Tx, res *abci.CheckTxResponse) int64
+
+func defaultPriority(tx types.Tx, res *abci.CheckTxResponse) int64 {
+	if len(tx) == 0 {
+		return 0
+	}
+	return res.GasWanted / int64(len(tx))
+}
+
+// priorityQueue is a container/heap.Interface max-heap over *clist.CElement,
+// ordered by the mempoolTx.priority each element's Value carries. It is the
+// secondary ordering index kept alongside the arrival-ordered CList: the
+// CList remains the source of truth for FIFO iteration (recheck, Flush),
+// while priorityQueue lets ReapMaxGas and eviction reach the
+// highest/lowest priority entries without a linear scan of the CList.
+type priorityQueue struct {
+	items []*clist.CElement
+}
+
+func (pq *priorityQueue) Len() int { return len(pq.items) }
+
+func (pq *priorityQueue) Less(i, j int) bool {
+	return pq.items[i].Value.(*mempoolTx).priority > pq.items[j].Value.(*mempoolTx).priority
+}
+
+func (pq *priorityQueue) Swap(i, j int) {
+	pq.items[i], pq.items[j] = pq.items[j], pq.items[i]
+	pq.items[i].Value.(*mempoolTx).heapIndex = i
+	pq.items[j].Value.(*mempoolTx).heapIndex = j
+}
+
+func (pq *priorityQueue) Push(x interface{}) {
+	e := x.(*clist.CElement)
+	e.Value.(*mempoolTx).heapIndex = len(pq.items)
+	pq.items = append(pq.items, e)
+}
+
+func (pq *priorityQueue) Pop() interface{} {
+	old := pq.items
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	pq.items = old[:n-1]
+	return e
+}
+
+// lowestPriority returns the entry with the smallest priority currently in
+// the index, or nil if the index is empty. Unlike the max-heap root, the
+// minimum isn't at a fixed slot, so this is a linear scan; it is only ever
+// called on the rare path where the mempool is full and a new tx is trying
+// to displace an existing one.
+func (pq *priorityQueue) lowestPriority() *clist.CElement {
+	if len(pq.items) == 0 {
+		return nil
+	}
+	lowest := pq.items[0]
+	lowestPriority := lowest.Value.(*mempoolTx).priority
+	for _, e := range pq.items[1:] {
+		if p := e.Value.(*mempoolTx).priority; p < lowestPriority {
+			lowest, lowestPriority = e, p
+		}
+	}
+	return lowest
+}
+
+var _ heap.Interface = (*priorityQueue)(nil)
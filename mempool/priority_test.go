@@ -0,0 +1,136 @@
+package mempool
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	abciclient "github.com/cometbft/cometbft/abci/client"
+	abciclimocks "github.com/cometbft/cometbft/abci/client/mocks"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	abci "github.com/cometbft/cometbft/api/cometbft/abci/v1"
+	"github.com/cometbft/cometbft/internal/clist"
+	"github.com/cometbft/cometbft/internal/test"
+	"github.com/cometbft/cometbft/types"
+)
+
+func TestDefaultPriorityGasPerByte(t *testing.T) {
+	require.Equal(t, int64(10), defaultPriority(make(types.Tx, 10), &abci.CheckTxResponse{GasWanted: 100}))
+	require.Equal(t, int64(0), defaultPriority(types.Tx{}, &abci.CheckTxResponse{GasWanted: 100}))
+}
+
+// mockClientWithPerTxGas is an instant, synchronous mock app whose CheckTx
+// response GasWanted is looked up from gasWanted by the raw tx bytes,
+// defaulting to 1 for any tx not present in the map. This lets tests drive
+// PriorityFunc's ordering independently of tx size.
+func mockClientWithPerTxGas(gasWanted map[string]int64) *abciclimocks.Client {
+	mockClient, callback := newMockABCIClient()
+
+	mockClient.On("CheckTxAsync", mock.Anything, mock.Anything).Run(
+		func(args mock.Arguments) {
+			req := args.Get(1).(*abci.CheckTxRequest)
+			abciReq := abcitypes.ToCheckTxRequest(req)
+
+			gas, ok := gasWanted[string(req.Tx)]
+			if !ok {
+				gas = 1
+			}
+			resp := &abci.CheckTxResponse{Code: abci.CodeTypeOK, GasWanted: gas}
+			ret := abciclient.NewReqRes(abciReq)
+			ret.Response = abcitypes.ToCheckTxResponse(resp)
+			(*callback)(abciReq, ret.Response)
+		},
+	).Return(func(_ context.Context, req *abci.CheckTxRequest) (*abciclient.ReqRes, error) {
+		abciReq := abcitypes.ToCheckTxRequest(req)
+		gas, ok := gasWanted[string(req.Tx)]
+		if !ok {
+			gas = 1
+		}
+		resp := &abci.CheckTxResponse{Code: abci.CodeTypeOK, GasWanted: gas}
+		ret := abciclient.NewReqRes(abciReq)
+		ret.Response = abcitypes.ToCheckTxResponse(resp)
+		return ret, nil
+	})
+
+	return mockClient
+}
+
+func newPriorityTestMempool(t *testing.T, gasWanted map[string]int64) *CListMempool {
+	t.Helper()
+	mockClient := mockClientWithPerTxGas(gasWanted)
+	conf := test.ResetTestRoot("mempool_priority_test")
+	mp, cleanup := newMempoolWithAppAndConfigMock(conf, mockClient)
+	t.Cleanup(cleanup)
+	return mp
+}
+
+func TestReapMaxGasOrdersByPriorityDescending(t *testing.T) {
+	gasWanted := map[string]int64{
+		"low":  1,
+		"mid":  50,
+		"high": 100,
+	}
+	mp := newPriorityTestMempool(t, gasWanted)
+
+	for _, name := range []string{"low", "high", "mid"} {
+		err := mp.CheckTx(types.Tx(name), TxInfo{}, nil, nil)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 3, mp.Size())
+
+	reaped := mp.ReapMaxGas(-1)
+	require.Equal(t, types.Txs{types.Tx("high"), types.Tx("mid"), types.Tx("low")}, reaped)
+}
+
+func TestFullMempoolEvictsLowerPriorityTx(t *testing.T) {
+	gasWanted := map[string]int64{
+		"low":  1,
+		"high": 100,
+	}
+	mp := newPriorityTestMempool(t, gasWanted)
+	mp.config.Size = 1
+
+	err := mp.CheckTx(types.Tx("low"), TxInfo{}, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, mp.Size())
+
+	err = mp.CheckTx(types.Tx("high"), TxInfo{}, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, mp.Size(), "higher priority tx should evict the lower priority one")
+
+	reaped := mp.ReapMaxGas(-1)
+	require.Equal(t, types.Txs{types.Tx("high")}, reaped)
+}
+
+// TestConcurrentEvictAndUpdateDoesNotPanic guards against a regression where
+// tryEvict (driven by a brand-new CheckTx response, which runs without
+// holding updateMtx) and Update's own commit-removal loop raced to remove
+// the same entry: removeTx used to unconditionally call mem.txs.Remove(e)
+// a second time and panic with "Remove(e) with false head/tail". Calling
+// removeTx twice for the same entry, from different goroutines, must now be
+// a no-op the second time around.
+func TestConcurrentEvictAndUpdateDoesNotPanic(t *testing.T) {
+	gasWanted := map[string]int64{"tx": 10}
+	mp := newPriorityTestMempool(t, gasWanted)
+
+	err := mp.CheckTx(types.Tx("tx"), TxInfo{}, nil, nil)
+	require.NoError(t, err)
+	e, ok := mp.txsMap.Load(types.Tx("tx").Key())
+	require.True(t, ok)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			defer wg.Done()
+			mp.removeTx(e.(*clist.CElement))
+		}()
+	}
+	wg.Wait()
+
+	_, ok = mp.txsMap.Load(types.Tx("tx").Key())
+	require.False(t, ok, "entry should be removed exactly once")
+}
@@ -0,0 +1,167 @@
+package mempool
+
+import (
+	"time"
+
+	protomem "github.com/cometbft/cometbft/api/cometbft/mempool/v1"
+	"github.com/cometbft/cometbft/config"
+	"github.com/cometbft/cometbft/internal/clist"
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cometbft/cometbft/p2p"
+	"github.com/cometbft/cometbft/types"
+)
+
+const (
+	MempoolChannel = byte(0x30)
+
+	peerCatchupSleepIntervalMS = 100
+)
+
+// Reactor handles mempool tx broadcasting amongst peers.
+// It maintains a map from peer ID to counter, to prevent gossiping txs to
+// the peers from which it received it.
+type Reactor struct {
+	p2p.BaseReactor
+	config  *config.MempoolConfig
+	mempool *CListMempool
+	ids     *mempoolIDs
+}
+
+// NewReactor returns a new Reactor with the given config and mempool.
+func NewReactor(config *config.MempoolConfig, mempool *CListMempool) *Reactor {
+	memR := &Reactor{
+		config:  config,
+		mempool: mempool,
+		ids:     newMempoolIDs(),
+	}
+	memR.BaseReactor = *p2p.NewBaseReactor("Mempool", memR)
+	return memR
+}
+
+// SetLogger sets the Logger on the reactor and the underlying mempool.
+func (memR *Reactor) SetLogger(l log.Logger) {
+	memR.Logger = l
+	memR.mempool.SetLogger(l)
+}
+
+// GetChannels implements Reactor.
+func (memR *Reactor) GetChannels() []*p2p.ChannelDescriptor {
+	largestTx := make([]byte, memR.config.MaxTxBytes)
+	batchMsg := protomem.Message{
+		Sum: &protomem.Message_Txs{
+			Txs: &protomem.Txs{Txs: [][]byte{largestTx}},
+		},
+	}
+	return []*p2p.ChannelDescriptor{
+		{
+			ID:                  MempoolChannel,
+			Priority:            5,
+			RecvMessageCapacity: batchMsg.Size(),
+			MessageType:         &protomem.Message{},
+		},
+	}
+}
+
+// InitPeer implements Reactor. It reserves the peer's compact SenderID
+// synchronously, before the switch can start delivering envelopes to
+// Receive or spawn AddPeer's broadcast routine, so neither ever observes
+// an unreserved peer.
+func (memR *Reactor) InitPeer(peer p2p.Peer) p2p.Peer {
+	memR.ids.ReserveForPeer(peer)
+	return peer
+}
+
+// AddPeer implements Reactor. It starts a broadcast routine ensuring all
+// txs are forwarded to the given peer.
+func (memR *Reactor) AddPeer(peer p2p.Peer) {
+	if memR.config.Broadcast {
+		go memR.broadcastTxRoutine(peer, memR.ids.GetForPeer(peer))
+	}
+}
+
+// RemovePeer implements Reactor. It releases the peer's reserved ID, so a
+// tx still carrying it as a sender doesn't leak the slot forever and the
+// ID becomes available for reuse.
+func (memR *Reactor) RemovePeer(peer p2p.Peer, _ interface{}) {
+	memR.ids.Reclaim(peer)
+}
+
+// Receive implements Reactor. It adds any received transactions to the
+// mempool, tagging them with the sending peer so the broadcast routine
+// below knows not to gossip them back.
+func (memR *Reactor) Receive(e p2p.Envelope) {
+	memR.Logger.Debug("Receive", "src", e.Src, "chId", e.ChannelID, "msg", e.Message)
+
+	msg, ok := e.Message.(*protomem.Txs)
+	if !ok {
+		memR.Logger.Error("received unknown message type", "src", e.Src, "chId", e.ChannelID)
+		return
+	}
+
+	txInfo := TxInfo{SenderID: memR.ids.GetForPeer(e.Src)}
+	if e.Src != nil {
+		txInfo.SenderP2PID = e.Src.ID()
+	}
+
+	for _, txBytes := range msg.Txs {
+		tx := types.Tx(txBytes)
+		err := memR.mempool.CheckTx(tx, txInfo, func(err error) {
+			memR.Logger.Info("Could not enqueue tx for checking", "tx", tx, "err", err)
+		}, nil)
+		// errCb above already logged an enqueue failure; this only covers
+		// rejections that never reach it, i.e. a PreCheckFunc failure.
+		if IsPreCheckError(err) {
+			memR.Logger.Info("Could not check tx", "tx", tx, "err", err)
+		}
+	}
+}
+
+// broadcastTxRoutine walks the mempool's CList forever (it blocks on
+// NextWaitChan instead of returning at the tail), sending each tx peer has
+// not already sent, as tracked by mempoolTx.senders. peerID is peer's
+// compact SenderID, already reserved by InitPeer before this routine was
+// spawned.
+func (memR *Reactor) broadcastTxRoutine(peer p2p.Peer, peerID uint16) {
+	var next *clist.CElement
+	for {
+		if !memR.IsRunning() || !peer.IsRunning() {
+			return
+		}
+
+		if next == nil {
+			select {
+			case <-memR.mempool.TxsWaitChan():
+			case <-memR.Quit():
+				return
+			case <-peer.Quit():
+				return
+			}
+			if next = memR.mempool.TxsFront(); next == nil {
+				continue
+			}
+		}
+
+		memTx := next.Value.(*mempoolTx)
+
+		if !memTx.HasSender(peerID) {
+			success := peer.Send(p2p.Envelope{
+				ChannelID: MempoolChannel,
+				Message:   &protomem.Txs{Txs: [][]byte{memTx.tx}},
+			})
+			if !success {
+				time.Sleep(peerCatchupSleepIntervalMS * time.Millisecond)
+				continue
+			}
+		}
+
+		afterCh := next.NextWaitChan()
+		select {
+		case <-afterCh:
+			next = next.Next()
+		case <-memR.Quit():
+			return
+		case <-peer.Quit():
+			return
+		}
+	}
+}
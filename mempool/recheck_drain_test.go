@@ -0,0 +1,107 @@
+package mempool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	abciclient "github.com/cometbft/cometbft/abci/client"
+	abciclimocks "github.com/cometbft/cometbft/abci/client/mocks"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	abci "github.com/cometbft/cometbft/api/cometbft/abci/v1"
+	"github.com/cometbft/cometbft/internal/test"
+	"github.com/cometbft/cometbft/types"
+)
+
+// mockClientWithControllableAsyncRecheck answers CHECK_TX_TYPE_CHECK
+// requests immediately, but defers the response to any
+// CHECK_TX_TYPE_RECHECK request to a goroutine that blocks on release,
+// simulating a real (non-mock) proxy app connection where recheck
+// responses arrive well after CheckTxAsync itself returns.
+func mockClientWithControllableAsyncRecheck(release <-chan struct{}) *abciclimocks.Client {
+	mockClient, callback := newMockABCIClient()
+
+	mockClient.On("CheckTxAsync", mock.Anything, mock.Anything).Run(
+		func(args mock.Arguments) {
+			req := args.Get(1).(*abci.CheckTxRequest)
+			abciReq := abcitypes.ToCheckTxRequest(req)
+			resp := &abci.CheckTxResponse{Code: abci.CodeTypeOK, GasWanted: 1}
+			ret := abciclient.NewReqRes(abciReq)
+			ret.Response = abcitypes.ToCheckTxResponse(resp)
+
+			if req.Type == abci.CHECK_TX_TYPE_RECHECK {
+				go func() {
+					<-release
+					(*callback)(abciReq, ret.Response)
+				}()
+				return
+			}
+			(*callback)(abciReq, ret.Response)
+		},
+	).Return(func(_ context.Context, req *abci.CheckTxRequest) (*abciclient.ReqRes, error) {
+		abciReq := abcitypes.ToCheckTxRequest(req)
+		resp := &abci.CheckTxResponse{Code: abci.CodeTypeOK, GasWanted: 1}
+		ret := abciclient.NewReqRes(abciReq)
+		ret.Response = abcitypes.ToCheckTxResponse(resp)
+		return ret, nil
+	})
+
+	return mockClient
+}
+
+// TestUpdateWaitsForPreviousRecheckRound guards against a regression where
+// Update(N)'s recheck submissions could still be in flight - unaccounted
+// for by anything - when Update(N+1) ran its own commit-removal loop for
+// the same tx, racing resCbRecheck's removeTx against Update's own and
+// risking a double-removal panic. Update must block on any outstanding
+// round from a previous Update before proceeding.
+func TestUpdateWaitsForPreviousRecheckRound(t *testing.T) {
+	release := make(chan struct{})
+	mockClient := mockClientWithControllableAsyncRecheck(release)
+	conf := test.ResetTestRoot("mempool_recheck_drain_test")
+	conf.Mempool.Recheck = true
+	mp, cleanup := newMempoolWithAppAndConfigMock(conf, mockClient)
+	defer cleanup()
+
+	err := mp.CheckTx(types.Tx("x"), TxInfo{}, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, mp.Size())
+
+	// Update(1) submits the recheck round for "x" and returns without
+	// waiting for its (still in-flight) response.
+	mp.Lock()
+	err = mp.Update(1, nil, nil, nil, nil)
+	mp.Unlock()
+	require.NoError(t, err)
+	require.Equal(t, 1, mp.Size(), "round 1's recheck response hasn't arrived yet")
+
+	// Update(2) commits "x". It must block until round 1's outstanding
+	// recheck response has been accounted for.
+	update2Done := make(chan struct{})
+	go func() {
+		mp.Lock()
+		err := mp.Update(2, types.Txs{types.Tx("x")}, nil, nil, nil)
+		mp.Unlock()
+		require.NoError(t, err)
+		close(update2Done)
+	}()
+
+	select {
+	case <-update2Done:
+		t.Fatal("Update(2) should block until round 1's recheck response is accounted for")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case <-update2Done:
+	case <-time.After(time.Second):
+		t.Fatal("Update(2) should proceed once round 1's recheck response arrives")
+	}
+
+	require.Equal(t, 0, mp.Size())
+}
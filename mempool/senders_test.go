@@ -0,0 +1,98 @@
+package mempool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	protomem "github.com/cometbft/cometbft/api/cometbft/mempool/v1"
+	"github.com/cometbft/cometbft/internal/clist"
+	"github.com/cometbft/cometbft/p2p"
+	p2pmock "github.com/cometbft/cometbft/p2p/mock"
+	"github.com/cometbft/cometbft/types"
+)
+
+func TestMempoolTxDuplicateFromDifferentPeerUpdatesSenders(t *testing.T) {
+	gasWanted := map[string]int64{"dup": 10}
+	mp := newPriorityTestMempool(t, gasWanted)
+
+	err := mp.CheckTx(types.Tx("dup"), TxInfo{SenderID: 1}, nil, nil)
+	require.NoError(t, err)
+
+	err = mp.CheckTx(types.Tx("dup"), TxInfo{SenderID: 2}, nil, nil)
+	require.ErrorIs(t, err, ErrTxInCache)
+
+	v, ok := mp.txsMap.Load(types.Tx("dup").Key())
+	require.True(t, ok)
+	memTx := v.(*clist.CElement).Value.(*mempoolTx)
+	require.True(t, memTx.HasSender(1))
+	require.True(t, memTx.HasSender(2))
+	require.False(t, memTx.HasSender(3))
+}
+
+func TestMempoolTxRemovalReleasesSenders(t *testing.T) {
+	gasWanted := map[string]int64{"evicted": 1, "evictor": 100}
+	mp := newPriorityTestMempool(t, gasWanted)
+	mp.config.Size = 1
+
+	err := mp.CheckTx(types.Tx("evicted"), TxInfo{SenderID: 1}, nil, nil)
+	require.NoError(t, err)
+
+	err = mp.CheckTx(types.Tx("evictor"), TxInfo{SenderID: 2}, nil, nil)
+	require.NoError(t, err)
+
+	_, ok := mp.txsMap.Load(types.Tx("evicted").Key())
+	require.False(t, ok, "evicted tx should no longer be tracked")
+}
+
+// recordingPeer wraps p2pmock.Peer, recording every tx broadcastTxRoutine
+// sends to it so tests can assert on what was (or wasn't) gossiped.
+type recordingPeer struct {
+	*p2pmock.Peer
+	sent chan types.Tx
+}
+
+func newRecordingPeer() *recordingPeer {
+	return &recordingPeer{Peer: p2pmock.NewPeer(nil), sent: make(chan types.Tx, 10)}
+}
+
+func (p *recordingPeer) Send(e p2p.Envelope) bool {
+	p.sent <- types.Tx(e.Message.(*protomem.Txs).Txs[0])
+	return true
+}
+
+// TestBroadcastTxRoutineSkipsKnownSenders covers chunk0-3's requirement
+// that broadcastTxRoutine never gossips a tx back to the peer it was
+// received from, as tracked by mempoolTx.senders.
+func TestBroadcastTxRoutineSkipsKnownSenders(t *testing.T) {
+	gasWanted := map[string]int64{"from-peer": 1, "from-elsewhere": 1}
+	mp := newPriorityTestMempool(t, gasWanted)
+
+	memR := NewReactor(mp.config, mp)
+	require.NoError(t, memR.Start())
+	t.Cleanup(func() { _ = memR.Stop() })
+
+	peer := newRecordingPeer()
+	memR.InitPeer(peer)
+	peerID := memR.ids.GetForPeer(peer)
+	require.NotEqual(t, UnknownPeerID, peerID)
+
+	require.NoError(t, mp.CheckTx(types.Tx("from-peer"), TxInfo{SenderID: peerID}, nil, nil))
+	require.NoError(t, mp.CheckTx(types.Tx("from-elsewhere"), TxInfo{SenderID: peerID + 1}, nil, nil))
+
+	go memR.broadcastTxRoutine(peer, peerID)
+
+	select {
+	case tx := <-peer.sent:
+		require.Equal(t, types.Tx("from-elsewhere"), tx, "broadcast should skip the tx peer itself sent")
+	case <-time.After(time.Second):
+		t.Fatal("broadcastTxRoutine never sent the tx peer hadn't already seen")
+	}
+
+	select {
+	case tx := <-peer.sent:
+		t.Fatalf("broadcastTxRoutine sent %q back to the peer it came from", tx)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
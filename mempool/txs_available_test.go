@@ -0,0 +1,101 @@
+package mempool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cometbft/cometbft/config"
+)
+
+// TestTxsAvailableFiresExactlyOncePerHeight mirrors the concurrent
+// Update/Reap tests: many CheckTx calls race a concurrent Update, and
+// TxsAvailable must fire exactly once for the height despite that.
+func TestTxsAvailableFiresExactlyOncePerHeight(t *testing.T) {
+	mp, _, cleanup := setupConcurrentUpdateReapTest(t, 0, func(conf *config.Config) {})
+	defer cleanup()
+	mp.EnableTxsAvailable()
+
+	doneUpdating, wg := &atomic.Bool{}, &sync.WaitGroup{}
+	asyncRunEmptyUpdateWithWg(t, mp, doneUpdating, wg)
+
+	var fired atomic.Int32
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-mp.TxsAvailable():
+			fired.Add(1)
+		case <-time.After(time.Second):
+		}
+	}()
+
+	checkTxs(t, mp, 5)
+
+	wg.Wait()
+	require.Equal(t, int32(1), fired.Load(), "TxsAvailable should fire exactly once for the height")
+
+	select {
+	case <-mp.TxsAvailable():
+		t.Fatal("TxsAvailable should not fire a second time for the same height")
+	default:
+	}
+}
+
+func TestTxsAvailableRearmsAfterUpdate(t *testing.T) {
+	mp, _, cleanup := setupConcurrentUpdateReapTest(t, 0, func(conf *config.Config) {})
+	defer cleanup()
+	mp.EnableTxsAvailable()
+
+	checkTxs(t, mp, 1)
+	select {
+	case <-mp.TxsAvailable():
+	case <-time.After(time.Second):
+		t.Fatal("TxsAvailable should have fired after the first tx")
+	}
+
+	mp.Lock()
+	err := mp.Update(1, nil, nil, nil, nil)
+	mp.Unlock()
+	require.NoError(t, err)
+
+	checkTxs(t, mp, 1)
+	select {
+	case <-mp.TxsAvailable():
+	case <-time.After(time.Second):
+		t.Fatal("TxsAvailable should fire again after being re-armed by Update")
+	}
+}
+
+// TestTxsAvailableFiresOnUpdateWithSurvivingTxs covers a block that commits
+// none of the pending txs (e.g. it was byte/gas-limited, or consensus built
+// an intentionally empty block under CreateEmptyBlocks=false): the mempool
+// is left non-empty by Update itself, with no further CheckTx to trigger
+// notifyTxsAvailable, so Update must fire TxsAvailable directly.
+func TestTxsAvailableFiresOnUpdateWithSurvivingTxs(t *testing.T) {
+	mp, _, cleanup := setupConcurrentUpdateReapTest(t, 0, func(conf *config.Config) {})
+	defer cleanup()
+	mp.EnableTxsAvailable()
+
+	checkTxs(t, mp, 1)
+	select {
+	case <-mp.TxsAvailable():
+	case <-time.After(time.Second):
+		t.Fatal("TxsAvailable should have fired after the initial tx")
+	}
+
+	mp.Lock()
+	err := mp.Update(1, nil, nil, nil, nil)
+	mp.Unlock()
+	require.NoError(t, err)
+	require.Equal(t, 1, mp.Size(), "the tx was not in the committed block, so it should remain")
+
+	select {
+	case <-mp.TxsAvailable():
+	case <-time.After(time.Second):
+		t.Fatal("TxsAvailable should fire from Update itself since a tx survived the update")
+	}
+}